@@ -17,7 +17,6 @@ import (
 	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/hive.go/netutil"
 
-	"github.com/gohornet/hornet/packages/autopeering/services"
 	"github.com/gohornet/hornet/packages/parameter"
 	"github.com/gohornet/hornet/plugins/autopeering/local"
 )
@@ -34,70 +33,104 @@ var (
 	ErrParsingEntryNode = errors.New("can't parse entry node")
 
 	log *logger.Logger
+
+	// entryNodeIDs holds the IDs of the configured entry nodes, so wireEvents
+	// can tell a dropped entry node apart from a dropped regular peer and
+	// trigger Events.EntryNodeUnreachable accordingly.
+	entryNodeIDs map[string]bool
 )
 
 func configureAP() {
+	applyNodeKeySeed()
+	configurePeerScoring()
+
 	entryNodes, err := parseEntryNodes()
 	if err != nil {
 		log.Errorf("Invalid entry nodes; ignoring: %v", err)
 	}
 	log.Debugf("Entry node peers: %v", entryNodes)
 
+	entryNodeIDs = make(map[string]bool, len(entryNodes))
+	for _, entryNode := range entryNodes {
+		entryNodeIDs[peerIDFromPeer(entryNode)] = true
+	}
+
 	Discovery = discover.New(local.GetInstance(), discover.Logger(log.Named("disc")), discover.MasterPeers(entryNodes))
 
-	// enable peer selection only when gossip is enabled
-	Selection = selection.New(local.GetInstance(), Discovery, selection.Logger(log.Named("sel")), selection.NeighborValidator(selection.ValidatorFunc(isValidNeighbor)))
+	// enable peer selection only when running full gossip peering; a
+	// standalone entry node only ever does discovery
+	if !isEntryNodeMode() {
+		Selection = selection.New(local.GetInstance(), Discovery, selection.Logger(log.Named("sel")), selection.NeighborValidator(isValidNeighbor(buildValidatorChain())))
+	}
+
+	wireEvents()
 }
 
-// isValidNeighbor checks whether a peer is a valid neighbor.
-func isValidNeighbor(p *peer.Peer) bool {
-	// gossip must be supported
-	gossipAddr := p.Services().Get(services.GossipServiceKey())
-	if gossipAddr == nil {
-		return false
-	}
-	// the host for the gossip and peering service must be identical
-	gossipHost, _, err := net.SplitHostPort(gossipAddr.String())
-	if err != nil {
-		return false
-	}
-	peeringAddr := p.Services().Get(service.PeeringKey)
-	peeringHost, _, err := net.SplitHostPort(peeringAddr.String())
-	if err != nil {
-		return false
+// isValidNeighbor wraps a ValidatorChain with the ban-list check, which
+// always takes precedence over the configurable validators.
+func isValidNeighbor(chain *ValidatorChain) selection.ValidatorFunc {
+	return func(p *peer.Peer) bool {
+		if isBanned(peerIDFromPeer(p)) {
+			return false
+		}
+		return chain.Validate(p)
 	}
-	return gossipHost == peeringHost
 }
 
 func start(shutdownSignal <-chan struct{}) {
 	defer log.Info("Stopping Autopeering ... done")
 
 	lPeer := local.GetInstance()
-	// use the port of the peering service
-	peeringAddr := lPeer.Services().Get(service.PeeringKey)
-	_, peeringPort, err := net.SplitHostPort(peeringAddr.String())
-	if err != nil {
-		panic(err)
-	}
-	// resolve the bind address
-	address := net.JoinHostPort(parameter.NodeConfig.GetString(local.CFG_BIND), peeringPort)
-	localAddr, err := net.ResolveUDPAddr(peeringAddr.Network(), address)
-	if err != nil {
-		log.Fatalf("Error resolving %s: %v", local.CFG_BIND, err)
+
+	// bind and listen on whichever address families the local peer advertises
+	var v4Conn, v6Conn *net.UDPConn
+	var primaryAddr net.Addr
+
+	if peeringAddr := lPeer.Services().Get(service.PeeringKey); peeringAddr != nil {
+		localAddr, err := resolveBindAddr(peeringAddr)
+		if err != nil {
+			log.Fatalf("Error resolving %s: %v", local.CFG_BIND, err)
+		}
+		log.Info("Testing service (IPv4) ...")
+		defer ensureReachable(localAddr, peeringAddr, service.PeeringKey, isEntryNodeMode(), shutdownSignal)()
+		log.Info("Testing service (IPv4) ... done")
+
+		v4Conn, err = net.ListenUDP(peeringAddr.Network(), localAddr)
+		if err != nil {
+			log.Fatalf("Error listening: %v", err)
+		}
+		defer v4Conn.Close()
+		primaryAddr = peeringAddr
 	}
 
-	// check that discovery is working and the port is open
-	log.Info("Testing service ...")
-	checkConnection(localAddr, &lPeer.Peer)
-	log.Info("Testing service ... done")
+	if peeringAddrV6 := lPeer.Services().Get(peeringKeyIPv6); peeringAddrV6 != nil {
+		localAddr, err := resolveBindAddr(peeringAddrV6)
+		if err != nil {
+			log.Fatalf("Error resolving %s: %v", local.CFG_BIND, err)
+		}
+		log.Info("Testing service (IPv6) ...")
+		defer ensureReachable(localAddr, peeringAddrV6, peeringKeyIPv6, isEntryNodeMode(), shutdownSignal)()
+		log.Info("Testing service (IPv6) ... done")
 
-	conn, err := net.ListenUDP(peeringAddr.Network(), localAddr)
-	if err != nil {
-		log.Fatalf("Error listening: %v", err)
+		v6Conn, err = net.ListenUDP(peeringAddrV6.Network(), localAddr)
+		if err != nil {
+			log.Fatalf("Error listening: %v", err)
+		}
+		defer v6Conn.Close()
+		if primaryAddr == nil {
+			primaryAddr = peeringAddrV6
+		}
 	}
+
+	if v4Conn == nil && v6Conn == nil {
+		log.Panicf("No peering service (IPv4 or IPv6) configured for the local peer")
+	}
+
+	// multiplex both sockets (when present) behind a single net.PacketConn
+	conn := newDualStackConn(v4Conn, v6Conn)
 	defer conn.Close()
 
-	// use the UDP connection for transport
+	// use the UDP connection(s) for transport
 	trans := transport.Conn(conn, func(network, address string) (net.Addr, error) { return net.ResolveUDPAddr(network, address) })
 	defer trans.Close()
 
@@ -120,15 +153,24 @@ func start(shutdownSignal <-chan struct{}) {
 		defer Selection.Close()
 	}
 
-	log.Infof(name+" started: Address=%s/%s", peeringAddr.String(), peeringAddr.Network())
+	log.Infof(name+" started: Address=%s/%s", primaryAddr.String(), primaryAddr.Network())
 
 	ID = lPeer.ID().String()
 	log.Infof(name+" started: ID=%s PublicKey=%s", ID, base64.StdEncoding.EncodeToString(lPeer.PublicKey()))
 
+	if isEntryNodeMode() {
+		printEntryNodeInfo(&lPeer.Peer)
+	}
+
 	<-shutdownSignal
 	log.Info("Stopping Autopeering ...")
 }
 
+// parseEntryNodes parses the configured entry nodes into peers, each carrying
+// a service.Record with an IPv4 and/or IPv6 peering endpoint. An entry node
+// definition may list more than one peering transport, separated by commas
+// (e.g. "pubkey@host:port,host2:port2"), to cover the case where the entry
+// node advertises its addresses across multiple hosts.
 func parseEntryNodes() (result []*peer.Peer, err error) {
 	for _, entryNodeDefinition := range parameter.NodeConfig.GetStringSlice(CFG_ENTRY_NODES) {
 		if entryNodeDefinition == "" {
@@ -144,37 +186,112 @@ func parseEntryNodes() (result []*peer.Peer, err error) {
 			return nil, fmt.Errorf("%w: can't decode public key: %s", ErrParsingEntryNode, err)
 		}
 
-		entryAddr, err := iputils.ParseOriginAddress(parts[1])
-		if err != nil {
-			return nil, fmt.Errorf("%w: invalid entry node address %s", err, parts[1])
-		}
+		services := service.New()
+		for _, transportDefinition := range strings.Split(parts[1], ",") {
+			entryAddr, err := iputils.ParseOriginAddress(transportDefinition)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid entry node address %s", err, transportDefinition)
+			}
 
-		ipAddresses, err := iputils.GetIPAddressesFromHost(entryAddr.Addr)
-		if err != nil {
-			return nil, fmt.Errorf("%w: while handling %s", err, parts[1])
+			ipAddresses, err := iputils.GetIPAddressesFromHost(entryAddr.Addr)
+			if err != nil {
+				return nil, fmt.Errorf("%w: while handling %s", err, transportDefinition)
+			}
+
+			// GetPreferredAddress(false/true) picks the best IPv4/IPv6 candidate
+			// respectively; when a host only resolves to one family both calls
+			// return the same address, so the net.IP family check below dedupes.
+			if ip4 := ipAddresses.GetPreferredAddress(false); ip4 != nil && net.ParseIP(ip4.ToString()).To4() != nil && services.Get(service.PeeringKey) == nil {
+				services.Update(service.PeeringKey, "udp", fmt.Sprintf("%s:%d", ip4.ToString(), entryAddr.Port))
+			}
+			if ip6 := ipAddresses.GetPreferredAddress(true); ip6 != nil && net.ParseIP(ip6.ToString()).To4() == nil {
+				ip6Addr := fmt.Sprintf("[%s]:%d", ip6.ToString(), entryAddr.Port)
+				// discover.Protocol only resolves service.PeeringKey when
+				// looking up an entry node's peering endpoint, so an IPv6-only
+				// entry node must advertise under that key to be usable at
+				// all; peeringKeyIPv6 is only a secondary, best-effort slot
+				// for the dual-stack case (see dualstack.go).
+				if services.Get(service.PeeringKey) == nil {
+					services.Update(service.PeeringKey, "udp", ip6Addr)
+				} else if services.Get(peeringKeyIPv6) == nil {
+					services.Update(peeringKeyIPv6, "udp", ip6Addr)
+				}
+			}
+		}
+		if services.Get(service.PeeringKey) == nil && services.Get(peeringKeyIPv6) == nil {
+			return nil, fmt.Errorf("%w: no usable address found for %s", ErrParsingEntryNode, parts[1])
 		}
 
-		services := service.New()
-		ip := ipAddresses.GetPreferredAddress(parameter.NodeConfig.GetBool("network.prefer_ipv6")).ToString()
-		services.Update(service.PeeringKey, "udp", fmt.Sprintf("%s:%d", ip, entryAddr.Port))
 		result = append(result, peer.NewPeer(pubKey, services))
 	}
 
 	return result, nil
 }
 
-func checkConnection(localAddr *net.UDPAddr, self *peer.Peer) {
-	peering := self.Services().Get(service.PeeringKey)
-	remoteAddr, err := net.ResolveUDPAddr(peering.Network(), peering.String())
+// resolveBindAddr resolves the local bind address matching the given
+// advertised peering address (same network/address family, but using the
+// locally configured bind interface).
+func resolveBindAddr(peeringAddr net.Addr) (*net.UDPAddr, error) {
+	_, peeringPort, err := net.SplitHostPort(peeringAddr.String())
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	address := net.JoinHostPort(parameter.NodeConfig.GetString(local.CFG_BIND), peeringPort)
+	return net.ResolveUDPAddr(peeringAddr.Network(), address)
+}
 
-	// do not check the address as a NAT may change them for local connections
-	err = netutil.CheckUDP(localAddr, remoteAddr, false, true)
+// ensureReachable verifies that the peering service at localAddr/peering is
+// publicly reachable. If it isn't, it attempts a NAT-PMP/UPnP-IGD port
+// mapping instead of giving up immediately: on success the local peer's
+// advertised service is updated to the mapped external address, and a
+// background watcher keeps it in sync with the router-reported external IP.
+// Only if neither direct reachability nor NAT mapping works does it panic.
+// The returned func releases the port mapping, if one was made, and should
+// be deferred by the caller. shutdownSignal stops the background external-IP
+// watcher, if one was started, once the plugin shuts down.
+func ensureReachable(localAddr *net.UDPAddr, peering net.Addr, key service.Key, skipPanic bool, shutdownSignal <-chan struct{}) func() {
+	if err := checkConnection(localAddr, peering); err == nil {
+		return func() {}
+	} else {
+		log.Infof("Service not directly reachable (%s); attempting NAT traversal ...", err)
+	}
+
+	externalAddr, watchChanges, unmap, ok := setupNAT(localAddr)
+	if !ok {
+		if skipPanic {
+			// a standalone entry node doesn't need to be reachable from
+			// behind a NAT, e.g. when fronted by a reachable public address
+			log.Warnf("Service at %s/%s is not publicly reachable and no NAT mapping could be made",
+				peering.String(), peering.Network())
+			return func() {}
+		}
+		log.Panicf("Please check that HORNET is publicly reachable at %s/%s, or configure %s",
+			peering.String(), peering.Network(), CFG_NAT_MODE)
+	}
+
+	local.GetInstance().Services().Update(key, "udp", externalAddr.String())
+
+	watchChanges(func(ip net.IP) {
+		newAddr := &net.UDPAddr{IP: ip, Port: externalAddr.Port}
+		// Discovery reads the local peer's services live on every request it
+		// answers, so updating the record here is enough to propagate the
+		// change to the network on the next round.
+		local.GetInstance().Services().Update(key, "udp", newAddr.String())
+		log.Infof("Re-announced %s endpoint as %s after external IP change", key, newAddr)
+		Events.ReachabilityChanged.Trigger(key, newAddr.String())
+	}, shutdownSignal)
+
+	return unmap
+}
+
+// checkConnection reports whether the peering service at peering is publicly
+// reachable from localAddr.
+func checkConnection(localAddr *net.UDPAddr, peering net.Addr) error {
+	remoteAddr, err := net.ResolveUDPAddr(peering.Network(), peering.String())
 	if err != nil {
-		log.Errorf("Error testing service: %s", err)
-		log.Panicf("Please check that HORNET is publicly reachable at %s/%s",
-			peering.String(), peering.Network())
+		return err
 	}
-}
\ No newline at end of file
+
+	// do not check the address as a NAT may change them for local connections
+	return netutil.CheckUDP(localAddr, remoteAddr, false, true)
+}
@@ -0,0 +1,109 @@
+package autopeering
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+
+	"github.com/gohornet/hornet/packages/parameter"
+)
+
+func testPubKey(t *testing.T) string {
+	t.Helper()
+	// parseEntryNodes only base64-decodes the key, it never validates its
+	// length/curve, so any decodable payload is enough for these tests.
+	return base64.StdEncoding.EncodeToString([]byte("0123456789012345678901234567890b"))
+}
+
+func TestParseEntryNodesIPv4(t *testing.T) {
+	defer parameter.NodeConfig.Set(CFG_ENTRY_NODES, nil)
+
+	pubKey := testPubKey(t)
+	parameter.NodeConfig.Set(CFG_ENTRY_NODES, []string{pubKey + "@127.0.0.1:14626"})
+
+	peers, err := parseEntryNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 entry node peer, got %d", len(peers))
+	}
+	if addr := peers[0].Services().Get(service.PeeringKey); addr == nil {
+		t.Fatalf("expected an IPv4 peering endpoint under service.PeeringKey")
+	} else if addr.String() != "127.0.0.1:14626" {
+		t.Fatalf("unexpected peering address: %s", addr.String())
+	}
+	if addr := peers[0].Services().Get(peeringKeyIPv6); addr != nil {
+		t.Fatalf("did not expect an IPv6 peering endpoint, got %s", addr.String())
+	}
+}
+
+func TestParseEntryNodesIPv6Only(t *testing.T) {
+	defer parameter.NodeConfig.Set(CFG_ENTRY_NODES, nil)
+
+	pubKey := testPubKey(t)
+	parameter.NodeConfig.Set(CFG_ENTRY_NODES, []string{pubKey + "@[::1]:14626"})
+
+	peers, err := parseEntryNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 entry node peer, got %d", len(peers))
+	}
+	// an IPv6-only entry node must be reachable via discover.Protocol, which
+	// only ever looks up service.PeeringKey.
+	if addr := peers[0].Services().Get(service.PeeringKey); addr == nil {
+		t.Fatalf("expected the IPv6 endpoint to be advertised under service.PeeringKey")
+	}
+	if addr := peers[0].Services().Get(peeringKeyIPv6); addr != nil {
+		t.Fatalf("did not expect peeringKeyIPv6 to be populated when there is no IPv4 endpoint, got %s", addr.String())
+	}
+}
+
+func TestParseEntryNodesMultiTransport(t *testing.T) {
+	defer parameter.NodeConfig.Set(CFG_ENTRY_NODES, nil)
+
+	pubKey := testPubKey(t)
+	parameter.NodeConfig.Set(CFG_ENTRY_NODES, []string{pubKey + "@127.0.0.1:14626,[::1]:14626"})
+
+	peers, err := parseEntryNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 entry node peer, got %d", len(peers))
+	}
+	if addr := peers[0].Services().Get(service.PeeringKey); addr == nil || addr.String() != "127.0.0.1:14626" {
+		t.Fatalf("expected the IPv4 endpoint under service.PeeringKey, got %v", addr)
+	}
+	if addr := peers[0].Services().Get(peeringKeyIPv6); addr == nil {
+		t.Fatalf("expected the IPv6 endpoint under peeringKeyIPv6 since IPv4 already claimed service.PeeringKey")
+	}
+}
+
+func TestParseEntryNodesInvalidFormat(t *testing.T) {
+	defer parameter.NodeConfig.Set(CFG_ENTRY_NODES, nil)
+
+	parameter.NodeConfig.Set(CFG_ENTRY_NODES, []string{"not-a-valid-entry-node"})
+
+	if _, err := parseEntryNodes(); !errors.Is(err, ErrParsingEntryNode) {
+		t.Fatalf("expected ErrParsingEntryNode, got %v", err)
+	}
+}
+
+func TestParseEntryNodesSkipsEmpty(t *testing.T) {
+	defer parameter.NodeConfig.Set(CFG_ENTRY_NODES, nil)
+
+	parameter.NodeConfig.Set(CFG_ENTRY_NODES, []string{""})
+
+	peers, err := parseEntryNodes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("expected no entry node peers, got %d", len(peers))
+	}
+}
@@ -0,0 +1,96 @@
+package autopeering
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/iotaledger/hive.go/autopeering/peer"
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+
+	"github.com/gohornet/hornet/packages/parameter"
+	"github.com/gohornet/hornet/plugins/autopeering/local"
+)
+
+const (
+	// CFG_RUN_AS_ENTRY_NODE mirrors the -bootnode flag as a config option, so
+	// operators can bake it into a config file instead of a runtime flag.
+	CFG_RUN_AS_ENTRY_NODE = "autopeering.runAsEntryNode"
+)
+
+var (
+	// registered on pflag.CommandLine, like the rest of hornet's CLI flags,
+	// so the stdlib flag package's own (unparsed) FlagSet doesn't silently
+	// swallow them.
+	bootnodeFlag = pflag.Bool("bootnode", false, "run as a standalone autopeering entry node (discovery only, no gossip peering)")
+	nodeKeyFile  = pflag.String("nodekeyfile", "", "path to a base64-encoded node private key seed; a new one is generated and stored there if the file doesn't exist")
+)
+
+// isEntryNodeMode reports whether the plugin should run as a standalone
+// entry node: only Discovery is started, Selection (gossip peering) is left
+// disabled entirely.
+func isEntryNodeMode() bool {
+	return *bootnodeFlag || parameter.NodeConfig.GetBool(CFG_RUN_AS_ENTRY_NODE)
+}
+
+// loadOrCreateNodeKeySeed loads a persisted base64-encoded seed from
+// --nodekeyfile, generating and storing a fresh one if the file doesn't
+// exist yet. Returns nil if --nodekeyfile wasn't given, in which case the
+// local peer falls back to its regular (ephemeral or configured) key.
+func loadOrCreateNodeKeySeed(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err == nil {
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("can't generate node key seed: %w", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(seed)), 0600); err != nil {
+		return nil, fmt.Errorf("can't persist node key seed to %s: %w", path, err)
+	}
+	log.Infof("Generated new node key seed, stored at %s", path)
+	return seed, nil
+}
+
+// printEntryNodeInfo prints the local peer's public key and peering address
+// in the exact "pubkey@host:port" format parseEntryNodes expects, so an
+// operator can copy it straight into other nodes' entryNodes config.
+func printEntryNodeInfo(lPeer *peer.Peer) {
+	peeringAddr := lPeer.Services().Get(service.PeeringKey)
+	if peeringAddr == nil {
+		peeringAddr = lPeer.Services().Get(peeringKeyIPv6)
+	}
+	if peeringAddr == nil {
+		return
+	}
+	fmt.Printf("%s@%s\n", base64.StdEncoding.EncodeToString(lPeer.PublicKey()), peeringAddr.String())
+}
+
+// applyNodeKeySeed loads/creates the --nodekeyfile seed, if configured, and
+// makes it available to local.GetInstance() via the same config key it
+// would otherwise read a configured seed from.
+func applyNodeKeySeed() {
+	seed, err := loadOrCreateNodeKeySeed(*nodeKeyFile)
+	if err != nil {
+		log.Fatalf("Error loading node key seed: %v", err)
+	}
+	if seed == nil {
+		return
+	}
+	parameter.NodeConfig.Set(local.CFG_SEED, base64.StdEncoding.EncodeToString(seed))
+}
@@ -0,0 +1,147 @@
+package autopeering
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+)
+
+// peeringKeyIPv6 is the service key under which a peer's secondary IPv6
+// peering endpoint is advertised when it already has an IPv4 endpoint under
+// service.PeeringKey. It is a hornet-local addition: discover.Protocol and
+// selection.Protocol only look up service.PeeringKey, so a v6-only peer (or
+// entry node) is advertised under service.PeeringKey instead, and this key is
+// only ever a best-effort second address for already-dual-stack peers.
+const peeringKeyIPv6 service.Key = "peering6"
+
+// packet is a single datagram read off one of the underlying sockets.
+type packet struct {
+	buf  []byte
+	n    int
+	addr net.Addr
+	err  error
+}
+
+// dualStackConn multiplexes an IPv4 and an IPv6 UDP socket behind a single
+// net.PacketConn, so that hive.go's transport.Conn can serve discovery and
+// peering over both address families on one server.Serve instance. Either
+// socket may be nil if that family isn't available.
+type dualStackConn struct {
+	v4, v6 *net.UDPConn
+
+	in        chan packet
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newDualStackConn(v4, v6 *net.UDPConn) *dualStackConn {
+	d := &dualStackConn{
+		v4:     v4,
+		v6:     v6,
+		in:     make(chan packet),
+		closed: make(chan struct{}),
+	}
+	if v4 != nil {
+		go d.readLoop(v4)
+	}
+	if v6 != nil {
+		go d.readLoop(v6)
+	}
+	return d
+}
+
+func (d *dualStackConn) readLoop(conn *net.UDPConn) {
+	for {
+		buf := make([]byte, 4096)
+		n, addr, err := conn.ReadFrom(buf)
+		select {
+		case d.in <- packet{buf: buf, n: n, addr: addr, err: err}:
+		case <-d.closed:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *dualStackConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case pkt := <-d.in:
+		n := copy(b, pkt.buf[:pkt.n])
+		return n, pkt.addr, pkt.err
+	case <-d.closed:
+		return 0, nil, io.ErrClosedPipe
+	}
+}
+
+func (d *dualStackConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, &net.AddrError{Err: "not a UDP address", Addr: addr.String()}
+	}
+	if udpAddr.IP.To4() != nil {
+		if d.v4 == nil {
+			return 0, &net.AddrError{Err: "no IPv4 socket available", Addr: addr.String()}
+		}
+		return d.v4.WriteTo(b, addr)
+	}
+	if d.v6 == nil {
+		return 0, &net.AddrError{Err: "no IPv6 socket available", Addr: addr.String()}
+	}
+	return d.v6.WriteTo(b, addr)
+}
+
+func (d *dualStackConn) Close() error {
+	var err error
+	d.closeOnce.Do(func() {
+		close(d.closed)
+		if d.v4 != nil {
+			if e := d.v4.Close(); e != nil {
+				err = e
+			}
+		}
+		if d.v6 != nil {
+			if e := d.v6.Close(); e != nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+func (d *dualStackConn) LocalAddr() net.Addr {
+	if d.v4 != nil {
+		return d.v4.LocalAddr()
+	}
+	return d.v6.LocalAddr()
+}
+
+func (d *dualStackConn) SetDeadline(t time.Time) error {
+	return d.forEach(func(c *net.UDPConn) error { return c.SetDeadline(t) })
+}
+
+func (d *dualStackConn) SetReadDeadline(t time.Time) error {
+	return d.forEach(func(c *net.UDPConn) error { return c.SetReadDeadline(t) })
+}
+
+func (d *dualStackConn) SetWriteDeadline(t time.Time) error {
+	return d.forEach(func(c *net.UDPConn) error { return c.SetWriteDeadline(t) })
+}
+
+func (d *dualStackConn) forEach(fn func(*net.UDPConn) error) error {
+	if d.v4 != nil {
+		if err := fn(d.v4); err != nil {
+			return err
+		}
+	}
+	if d.v6 != nil {
+		if err := fn(d.v6); err != nil {
+			return err
+		}
+	}
+	return nil
+}
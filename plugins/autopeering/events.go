@@ -0,0 +1,92 @@
+package autopeering
+
+import (
+	"github.com/iotaledger/hive.go/autopeering/discover"
+	"github.com/iotaledger/hive.go/autopeering/peer"
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+	"github.com/iotaledger/hive.go/autopeering/selection"
+	"github.com/iotaledger/hive.go/events"
+)
+
+// Events exposes the typed lifecycle events of the autopeering plugin so
+// other plugins (gossip, dashboard, metrics) can subscribe to state changes
+// instead of polling Discovery/Selection directly.
+var Events = pluginEvents{
+	PeerDiscovered:       events.NewEvent(peerCaller),
+	PeerDropped:          events.NewEvent(peerCaller),
+	NeighborAdded:        events.NewEvent(peerCaller),
+	NeighborRemoved:      events.NewEvent(peerCaller),
+	ReachabilityChanged:  events.NewEvent(reachabilityCaller),
+	EntryNodeUnreachable: events.NewEvent(entryNodeCaller),
+}
+
+type pluginEvents struct {
+	// PeerDiscovered is triggered when Discovery learns about a new peer.
+	PeerDiscovered *events.Event
+	// PeerDropped is triggered when a known peer is removed from Discovery
+	// (e.g. it stopped responding).
+	PeerDropped *events.Event
+	// NeighborAdded is triggered when Selection establishes a new gossip
+	// neighbor, either incoming or outgoing.
+	NeighborAdded *events.Event
+	// NeighborRemoved is triggered when a gossip neighbor is dropped.
+	NeighborRemoved *events.Event
+	// ReachabilityChanged is triggered when the locally advertised peering
+	// endpoint changes, e.g. after a NAT external-IP refresh.
+	ReachabilityChanged *events.Event
+	// EntryNodeUnreachable is triggered when a configured entry node stops
+	// responding to discovery handshakes and Discovery drops it.
+	EntryNodeUnreachable *events.Event
+}
+
+func peerCaller(handler interface{}, params ...interface{}) {
+	handler.(func(*peer.Peer))(params[0].(*peer.Peer))
+}
+
+func reachabilityCaller(handler interface{}, params ...interface{}) {
+	handler.(func(service.Key, string))(params[0].(service.Key), params[1].(string))
+}
+
+func entryNodeCaller(handler interface{}, params ...interface{}) {
+	handler.(func(*peer.Peer))(params[0].(*peer.Peer))
+}
+
+// wireEvents hooks the underlying discover.Protocol and selection.Protocol
+// events into the plugin-level Events, so Subscribers don't need to know
+// about the hive.go autopeering internals.
+func wireEvents() {
+	Discovery.Events().PeerDiscovered.Attach(events.NewClosure(func(ev *discover.PeerDiscoveredEvent) {
+		Events.PeerDiscovered.Trigger(ev.Peer)
+		// a peer only reaches Discovery's known-peers set after a successful
+		// UDP handshake, so this is also the handshake-success signal for scoring.
+		RegisterPeerEvent(peerIDFromPeer(ev.Peer), EventHandshakeSuccess, 0)
+	}))
+	Discovery.Events().PeerDeleted.Attach(events.NewClosure(func(ev *discover.PeerDeletedEvent) {
+		Events.PeerDropped.Trigger(ev.Peer)
+		// Discovery drops a peer once it stops responding to handshakes.
+		RegisterPeerEvent(peerIDFromPeer(ev.Peer), EventHandshakeFailure, 0)
+		if entryNodeIDs[peerIDFromPeer(ev.Peer)] {
+			Events.EntryNodeUnreachable.Trigger(ev.Peer)
+		}
+	}))
+
+	if Selection == nil {
+		return
+	}
+	Selection.Events().IncomingPeering.Attach(events.NewClosure(func(ev *selection.PeeringEvent) {
+		if ev.Status {
+			Events.NeighborAdded.Trigger(ev.Peer)
+			churn.record(peerIDFromPeer(ev.Peer))
+		}
+	}))
+	Selection.Events().OutgoingPeering.Attach(events.NewClosure(func(ev *selection.PeeringEvent) {
+		if ev.Status {
+			Events.NeighborAdded.Trigger(ev.Peer)
+			churn.record(peerIDFromPeer(ev.Peer))
+		}
+	}))
+	Selection.Events().Dropped.Attach(events.NewClosure(func(ev *selection.DroppedEvent) {
+		Events.NeighborRemoved.Trigger(ev.Peer)
+		churn.record(peerIDFromPeer(ev.Peer))
+	}))
+}
@@ -0,0 +1,167 @@
+package autopeering
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	natlib "github.com/libp2p/go-nat"
+
+	"github.com/gohornet/hornet/packages/parameter"
+)
+
+const (
+	// CFG_NAT_MODE selects the NAT traversal strategy for the peering socket:
+	// "off" disables it, "auto" races UPnP-IGD and NAT-PMP and keeps whichever
+	// responds first. "upnp"/"pmp" are accepted but, since go-nat v0.1.0 has no
+	// exported per-protocol discovery, currently behave like "auto"; they only
+	// reserve the distinct config values for when that becomes possible.
+	CFG_NAT_MODE = "network.nat.mode"
+
+	natModeOff  = "off"
+	natModeAuto = "auto"
+	natModeUPnP = "upnp"
+	natModePMP  = "pmp"
+
+	natDiscoveryTimeout = 5 * time.Second
+	natMappingDuration  = 20 * time.Minute
+	natRefreshInterval  = 10 * time.Minute
+
+	// natMappingRetries is how many times mapPort asks the router for a
+	// mapping before giving up. go-nat's AddPortMapping always maps the
+	// internal port passed in; the external port is the router's choice and
+	// can differ between attempts, so retrying can still succeed on a router
+	// that's already using our preferred external port, even though it's a
+	// no-op against a router that rejects the internal port itself (already
+	// in use, policy).
+	natMappingRetries    = 3
+	natMappingRetryDelay = 2 * time.Second
+)
+
+// setupNAT attempts to acquire an external port mapping for localAddr via
+// NAT-PMP and/or UPnP-IGD, as selected by network.nat.mode. It returns the
+// external address to advertise, a refresh func that starts a background
+// watcher calling onChange whenever the router-reported external IP changes,
+// and an unmap func to release the mapping on shutdown. ok is false if no
+// mapping could be established (mode is "off", no router responded, the
+// mapping was refused, or the external address could not be queried), and
+// the other return values are then nil.
+func setupNAT(localAddr *net.UDPAddr) (externalAddr *net.UDPAddr, refresh func(onChange func(net.IP), done <-chan struct{}), unmap func(), ok bool) {
+	mode := parameter.NodeConfig.GetString(CFG_NAT_MODE)
+	if mode == "" {
+		mode = natModeAuto
+	}
+	if mode == natModeOff {
+		return nil, nil, nil, false
+	}
+
+	gw, err := discoverGateway(mode)
+	if err != nil {
+		log.Infof("NAT traversal: no gateway found: %v", err)
+		return nil, nil, nil, false
+	}
+
+	extPort, err := mapPort(gw, localAddr.Port)
+	if err != nil {
+		log.Infof("NAT traversal: port mapping failed: %v", err)
+		return nil, nil, nil, false
+	}
+
+	extIP, err := gw.GetExternalAddress()
+	if err != nil {
+		log.Infof("NAT traversal: could not query external address: %v", err)
+		return nil, nil, nil, false
+	}
+
+	log.Infof("NAT traversal: mapped %s/udp -> %s:%d", localAddr, extIP, extPort)
+
+	externalAddr = &net.UDPAddr{IP: extIP, Port: extPort}
+	unmap = func() {
+		if err := gw.DeletePortMapping("udp", localAddr.Port); err != nil {
+			log.Warnf("NAT traversal: error removing port mapping: %v", err)
+		}
+	}
+	refresh = func(onChange func(net.IP), done <-chan struct{}) {
+		go refreshExternalIP(gw, extIP, onChange, done)
+	}
+	return externalAddr, refresh, unmap, true
+}
+
+// discoverGateway locates a NAT gateway, bounded by natDiscoveryTimeout so a
+// router that never answers can't hang start() forever. In "auto" mode,
+// UPnP-IGD and NAT-PMP discovery race each other and the first to succeed
+// wins; go-nat v0.1.0 has no protocol-specific discovery entry point, so
+// "upnp"/"pmp" run the same race under the same timeout (the router itself
+// still determines which protocol actually answers).
+func discoverGateway(mode string) (natlib.NAT, error) {
+	type result struct {
+		gw  natlib.NAT
+		err error
+	}
+	resCh := make(chan result, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), natDiscoveryTimeout)
+	defer cancel()
+
+	go func() {
+		gw, err := natlib.DiscoverGateway()
+		select {
+		case resCh <- result{gw, err}:
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.gw, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gateway discovery timed out after %s", natDiscoveryTimeout)
+	}
+}
+
+// mapPort requests a mapping for preferredPort, our UDP socket's fixed
+// internal port, retrying up to natMappingRetries times with a short delay on
+// failure. The internal port never changes between attempts (or unmap, which
+// deletes by localAddr.Port, would release the wrong mapping); the external
+// port is whatever the router assigns and may turn out different call to
+// call even for the same internal port, so a retry isn't guaranteed to help
+// against a router that simply rejects preferredPort outright.
+func mapPort(gw natlib.NAT, preferredPort int) (extPort int, err error) {
+	for attempt := 1; attempt <= natMappingRetries; attempt++ {
+		extPort, err = gw.AddPortMapping("udp", preferredPort, "hornet autopeering", natMappingDuration)
+		if err == nil {
+			return extPort, nil
+		}
+		log.Infof("NAT traversal: mapping %d failed (%v), attempt %d/%d", preferredPort, err, attempt, natMappingRetries)
+		if attempt < natMappingRetries {
+			time.Sleep(natMappingRetryDelay)
+		}
+	}
+	return 0, err
+}
+
+// refreshExternalIP periodically re-queries the router for the external IP
+// and invokes onChange whenever it differs from the last known value, so the
+// caller can re-announce the local peer's endpoint. It stops as soon as done
+// is closed, so the caller must close it when the plugin shuts down.
+func refreshExternalIP(gw natlib.NAT, lastIP net.IP, onChange func(net.IP), done <-chan struct{}) {
+	ticker := time.NewTicker(natRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ip, err := gw.GetExternalAddress()
+			if err != nil {
+				log.Warnf("NAT traversal: error refreshing external address: %v", err)
+				continue
+			}
+			if !ip.Equal(lastIP) {
+				log.Infof("NAT traversal: external IP changed %s -> %s", lastIP, ip)
+				lastIP = ip
+				onChange(ip)
+			}
+		}
+	}
+}
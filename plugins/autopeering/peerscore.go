@@ -0,0 +1,227 @@
+package autopeering
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/autopeering/peer"
+
+	"github.com/gohornet/hornet/packages/parameter"
+)
+
+// EventKind identifies the kind of observable event reported against a peer.
+type EventKind byte
+
+const (
+	// EventHandshakeSuccess is recorded when a UDP handshake with the peer succeeds.
+	EventHandshakeSuccess EventKind = iota
+	// EventHandshakeFailure is recorded when a UDP handshake with the peer fails.
+	EventHandshakeFailure
+	// EventGossipSessionEnd is recorded when a gossip session with the peer ends,
+	// carrying the session length (in seconds) as weight.
+	EventGossipSessionEnd
+	// EventInvalidMessage is recorded when the gossip plugin receives an invalid
+	// message from the peer.
+	EventInvalidMessage
+	// EventConnectionChurn is recorded when the peer repeatedly connects/disconnects
+	// in a short time frame.
+	EventConnectionChurn
+)
+
+const (
+	// CFG_PEER_SCORE_THRESHOLD is the score below which a peer is banned.
+	CFG_PEER_SCORE_THRESHOLD = "network.autopeering.scoreThreshold"
+	// CFG_PEER_BAN_DURATION is how long a banned peer stays on the bad-peer list.
+	CFG_PEER_BAN_DURATION = "network.autopeering.banDuration"
+	// CFG_PEER_SCORE_FILE is where the peer score/ban state is persisted between restarts.
+	CFG_PEER_SCORE_FILE = "network.autopeering.scoreFile"
+
+	defaultScoreThreshold = -50.0
+	defaultBanDuration    = 1 * time.Hour
+	defaultScoreFile      = "peerscore.json"
+
+	// churnWindow is the rolling window repeat-connect/disconnect cycles are
+	// counted over.
+	churnWindow = 5 * time.Minute
+	// churnThreshold is how many neighbor add or remove events inside
+	// churnWindow count as churn, feeding EventConnectionChurn into the scorer.
+	churnThreshold = 4
+)
+
+// eventWeights are the default score deltas applied for each event kind,
+// used when RegisterPeerEvent is called without an explicit weight override.
+var eventWeights = map[EventKind]float64{
+	EventHandshakeSuccess: 1,
+	EventHandshakeFailure: -5,
+	EventGossipSessionEnd: 0, // weight carries the session length, scaled by the caller
+	EventInvalidMessage:   -10,
+	EventConnectionChurn:  -3,
+}
+
+// banEntry records a banned peer and when the ban expires.
+type banEntry struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// peerScoreStore tracks per-peer scores and the bad-peer set, and persists
+// the bad-peer set to disk so bans survive a restart.
+type peerScoreStore struct {
+	sync.Mutex
+
+	threshold float64
+	banFor    time.Duration
+	path      string
+
+	scores map[string]float64
+	banned map[string]banEntry
+}
+
+var scoreStore *peerScoreStore
+
+// churnTracker counts, per peer, how many times it has been added/removed as
+// a gossip neighbor inside a rolling churnWindow. A peer that flaps in and
+// out that often is reported to the scorer as connection churn, distinct
+// from the individual NeighborAdded/NeighborRemoved events.
+type churnTracker struct {
+	sync.Mutex
+	recent map[string][]time.Time
+}
+
+var churn = &churnTracker{recent: make(map[string][]time.Time)}
+
+// record notes one add or remove event for peerID and, once churnThreshold of
+// them have landed inside churnWindow, feeds EventConnectionChurn into the
+// scorer and resets the count for that peer.
+func (c *churnTracker) record(peerID string) {
+	now := time.Now()
+	cutoff := now.Add(-churnWindow)
+
+	c.Lock()
+	defer c.Unlock()
+
+	kept := c.recent[peerID][:0]
+	for _, t := range c.recent[peerID] {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, now)
+
+	if len(kept) >= churnThreshold {
+		delete(c.recent, peerID)
+		RegisterPeerEvent(peerID, EventConnectionChurn, 0)
+		return
+	}
+	c.recent[peerID] = kept
+}
+
+// configurePeerScoring initializes the peer-scoring subsystem and loads any
+// persisted bad-peer set from disk.
+func configurePeerScoring() {
+	threshold := defaultScoreThreshold
+	if parameter.NodeConfig.IsSet(CFG_PEER_SCORE_THRESHOLD) {
+		threshold = parameter.NodeConfig.GetFloat64(CFG_PEER_SCORE_THRESHOLD)
+	}
+	banFor := defaultBanDuration
+	if parameter.NodeConfig.IsSet(CFG_PEER_BAN_DURATION) {
+		banFor = parameter.NodeConfig.GetDuration(CFG_PEER_BAN_DURATION)
+	}
+	path := defaultScoreFile
+	if parameter.NodeConfig.IsSet(CFG_PEER_SCORE_FILE) {
+		path = parameter.NodeConfig.GetString(CFG_PEER_SCORE_FILE)
+	}
+
+	scoreStore = &peerScoreStore{
+		threshold: threshold,
+		banFor:    banFor,
+		path:      path,
+		scores:    make(map[string]float64),
+		banned:    make(map[string]banEntry),
+	}
+	if err := scoreStore.load(); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not load peer score file %s: %v", path, err)
+	}
+}
+
+// RegisterPeerEvent feeds an observable event for the given peer into the
+// scoring subsystem. kind selects the default weight; weight, if non-zero,
+// replaces it (e.g. gossip session length in seconds).
+func RegisterPeerEvent(peerID string, kind EventKind, weight float64) {
+	if scoreStore == nil {
+		return
+	}
+	delta, ok := eventWeights[kind]
+	if !ok {
+		return
+	}
+	if weight != 0 {
+		delta = weight
+	}
+
+	scoreStore.Lock()
+	defer scoreStore.Unlock()
+
+	scoreStore.scores[peerID] += delta
+	if scoreStore.scores[peerID] < scoreStore.threshold {
+		scoreStore.banned[peerID] = banEntry{ExpiresAt: time.Now().Add(scoreStore.banFor)}
+		delete(scoreStore.scores, peerID)
+		log.Infof("Peer %s banned until %s (score fell below %.1f)", peerID, scoreStore.banned[peerID].ExpiresAt, scoreStore.threshold)
+		if err := scoreStore.save(); err != nil {
+			log.Warnf("Could not persist peer score file: %v", err)
+		}
+	}
+}
+
+// isBanned reports whether the given peer is currently on the bad-peer list.
+func isBanned(peerID string) bool {
+	if scoreStore == nil {
+		return false
+	}
+	scoreStore.Lock()
+	defer scoreStore.Unlock()
+
+	entry, ok := scoreStore.banned[peerID]
+	if !ok {
+		return false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		delete(scoreStore.banned, peerID)
+		return false
+	}
+	return true
+}
+
+func (s *peerScoreStore) load() error {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var banned map[string]banEntry
+	if err := json.Unmarshal(b, &banned); err != nil {
+		return err
+	}
+	s.banned = banned
+	return nil
+}
+
+func (s *peerScoreStore) save() error {
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(s.banned)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, 0600)
+}
+
+// peerIDFromPeer derives the bad-peer-set key for a discovered peer.
+func peerIDFromPeer(p *peer.Peer) string {
+	return p.ID().String()
+}
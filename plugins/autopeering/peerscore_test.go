@@ -0,0 +1,88 @@
+package autopeering
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gohornet/hornet/packages/parameter"
+)
+
+func configureTestScoring(t *testing.T, threshold float64, banFor time.Duration) {
+	t.Helper()
+	defer parameter.NodeConfig.Set(CFG_PEER_SCORE_THRESHOLD, nil)
+	defer parameter.NodeConfig.Set(CFG_PEER_BAN_DURATION, nil)
+	defer parameter.NodeConfig.Set(CFG_PEER_SCORE_FILE, nil)
+
+	parameter.NodeConfig.Set(CFG_PEER_SCORE_THRESHOLD, threshold)
+	parameter.NodeConfig.Set(CFG_PEER_BAN_DURATION, banFor)
+	parameter.NodeConfig.Set(CFG_PEER_SCORE_FILE, filepath.Join(t.TempDir(), "peerscore.json"))
+	configurePeerScoring()
+}
+
+func TestRegisterPeerEventBansBelowThreshold(t *testing.T) {
+	configureTestScoring(t, -4, time.Hour)
+
+	const peerID = "peerA"
+	RegisterPeerEvent(peerID, EventHandshakeFailure, 0) // weight -5, crosses the -4 threshold
+	if !isBanned(peerID) {
+		t.Fatalf("expected peer to be banned once its score fell below the threshold")
+	}
+	if _, ok := scoreStore.scores[peerID]; ok {
+		t.Fatalf("expected the score entry to be cleared once the peer was banned")
+	}
+}
+
+func TestRegisterPeerEventUnknownKindIsIgnored(t *testing.T) {
+	configureTestScoring(t, -50, time.Hour)
+
+	const peerID = "peerB"
+	RegisterPeerEvent(peerID, EventKind(255), 0)
+	if _, ok := scoreStore.scores[peerID]; ok {
+		t.Fatalf("expected no score entry for an unknown event kind")
+	}
+}
+
+func TestRegisterPeerEventWeightOverride(t *testing.T) {
+	configureTestScoring(t, -50, time.Hour)
+
+	const peerID = "peerC"
+	RegisterPeerEvent(peerID, EventGossipSessionEnd, 12)
+	if got := scoreStore.scores[peerID]; got != 12 {
+		t.Fatalf("expected score 12 from the weight override, got %v", got)
+	}
+}
+
+func TestIsBannedExpires(t *testing.T) {
+	configureTestScoring(t, -50, time.Hour)
+
+	const peerID = "peerD"
+	scoreStore.banned[peerID] = banEntry{ExpiresAt: time.Now().Add(-time.Minute)}
+	if isBanned(peerID) {
+		t.Fatalf("expected an expired ban entry to no longer count as banned")
+	}
+	if _, ok := scoreStore.banned[peerID]; ok {
+		t.Fatalf("expected isBanned to clean up the expired entry")
+	}
+}
+
+func TestChurnTrackerTriggersAfterThreshold(t *testing.T) {
+	configureTestScoring(t, -50, time.Hour)
+	churn = &churnTracker{recent: make(map[string][]time.Time)}
+
+	const peerID = "peerE"
+	for i := 0; i < churnThreshold-1; i++ {
+		churn.record(peerID)
+	}
+	if got := scoreStore.scores[peerID]; got != 0 {
+		t.Fatalf("expected no churn score before reaching churnThreshold, got %v", got)
+	}
+
+	churn.record(peerID)
+	if got, want := scoreStore.scores[peerID], eventWeights[EventConnectionChurn]; got != want {
+		t.Fatalf("expected churn score %v once churnThreshold was reached, got %v", want, got)
+	}
+	if len(churn.recent[peerID]) != 0 {
+		t.Fatalf("expected the rolling window to reset once churn was reported")
+	}
+}
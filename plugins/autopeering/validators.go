@@ -0,0 +1,457 @@
+package autopeering
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/iotaledger/hive.go/autopeering/peer"
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+	"github.com/iotaledger/hive.go/autopeering/selection"
+	"github.com/iotaledger/hive.go/events"
+
+	"github.com/gohornet/hornet/packages/autopeering/services"
+	"github.com/gohornet/hornet/packages/parameter"
+)
+
+const (
+	// CFG_P2P_VALIDATORS configures which neighbor validators are enabled and
+	// in what order they run, e.g. ["gossipHost", "subnet", "cidr"].
+	CFG_P2P_VALIDATORS = "p2p.validators"
+
+	// CFG_P2P_SUBNET_IPV4_MASK_BITS / CFG_P2P_SUBNET_IPV6_MASK_BITS define the
+	// subnet granularity used by the subnet validator.
+	CFG_P2P_SUBNET_IPV4_MASK_BITS = "p2p.subnet.ipv4MaskBits"
+	CFG_P2P_SUBNET_IPV6_MASK_BITS = "p2p.subnet.ipv6MaskBits"
+	// CFG_P2P_SUBNET_MAX_PEERS caps how many neighbors may share a subnet.
+	CFG_P2P_SUBNET_MAX_PEERS = "p2p.subnet.maxPeers"
+
+	// CFG_P2P_ASN_DB_PATH points at a MaxMind ASN database (GeoLite2-ASN.mmdb)
+	// used by the ASN diversity validator.
+	CFG_P2P_ASN_DB_PATH = "p2p.asn.dbPath"
+	// CFG_P2P_ASN_MAX_PEERS caps how many neighbors may share an ASN.
+	CFG_P2P_ASN_MAX_PEERS = "p2p.asn.maxPeers"
+
+	// CFG_P2P_MIN_VERSION is the minimum advertised protocol/software version
+	// required of a neighbor, in "major.minor.patch" form.
+	CFG_P2P_MIN_VERSION = "p2p.minVersion"
+
+	// CFG_P2P_ALLOWED_CIDRS / CFG_P2P_DENIED_CIDRS are optional allow/deny
+	// lists evaluated by the CIDR validator.
+	CFG_P2P_ALLOWED_CIDRS = "p2p.allowedCIDRs"
+	CFG_P2P_DENIED_CIDRS  = "p2p.deniedCIDRs"
+
+	defaultSubnetIPv4MaskBits = 24
+	defaultSubnetIPv6MaskBits = 64
+	defaultSubnetMaxPeers     = 3
+	defaultASNMaxPeers        = 5
+)
+
+// Validator decides whether a discovered peer may become a gossip neighbor.
+// It is intentionally stateless from the caller's perspective: any state a
+// validator needs (e.g. counts of already-accepted neighbors) it tracks
+// itself, from the NeighborAdded/NeighborRemoved events it subscribes to.
+type Validator interface {
+	// Name identifies the validator for logging and config toggles.
+	Name() string
+	// Validate reports whether p may become a neighbor.
+	Validate(p *peer.Peer) bool
+}
+
+// ValidatorChain runs a sequence of Validators, rejecting a peer as soon as
+// one of them does.
+type ValidatorChain struct {
+	validators []Validator
+}
+
+// NewValidatorChain builds a chain from the given validators, in order.
+func NewValidatorChain(validators ...Validator) *ValidatorChain {
+	return &ValidatorChain{validators: validators}
+}
+
+// Validate reports whether p passes every validator of the chain. It has the
+// signature of selection.ValidatorFunc; isValidNeighbor wraps it as one.
+func (c *ValidatorChain) Validate(p *peer.Peer) bool {
+	for _, v := range c.validators {
+		if !v.Validate(p) {
+			log.Debugf("Peer %s rejected by validator %q", p.ID(), v.Name())
+			return false
+		}
+	}
+	return true
+}
+
+// buildValidatorChain assembles the configured validator chain. Validators
+// are toggled and ordered via CFG_P2P_VALIDATORS; an empty/unset config
+// falls back to the historical gossip-host-equality-only behavior.
+func buildValidatorChain() *ValidatorChain {
+	names := parameter.NodeConfig.GetStringSlice(CFG_P2P_VALIDATORS)
+	if len(names) == 0 {
+		names = []string{"gossipHost"}
+	}
+
+	available := map[string]func() Validator{
+		"gossipHost": func() Validator { return gossipHostValidator{} },
+		"subnet":     newSubnetValidator,
+		"asn":        newASNValidator,
+		"version":    newMinVersionValidator,
+		"cidr":       newCIDRValidator,
+	}
+
+	var chain []Validator
+	for _, name := range names {
+		newValidator, ok := available[name]
+		if !ok {
+			log.Warnf("Unknown neighbor validator %q; ignoring", name)
+			continue
+		}
+		if v := newValidator(); v != nil {
+			chain = append(chain, v)
+		}
+	}
+	return NewValidatorChain(chain...)
+}
+
+// gossipHostValidator requires the gossip service to be advertised, and on
+// the same host as the peering service (the pre-existing behavior).
+type gossipHostValidator struct{}
+
+func (gossipHostValidator) Name() string { return "gossipHost" }
+
+func (gossipHostValidator) Validate(p *peer.Peer) bool {
+	gossipAddr := p.Services().Get(services.GossipServiceKey())
+	if gossipAddr == nil {
+		return false
+	}
+	gossipHost, _, err := net.SplitHostPort(gossipAddr.String())
+	if err != nil {
+		return false
+	}
+	peeringAddr := p.Services().Get(service.PeeringKey)
+	if peeringAddr == nil {
+		peeringAddr = p.Services().Get(peeringKeyIPv6)
+	}
+	if peeringAddr == nil {
+		return false
+	}
+	peeringHost, _, err := net.SplitHostPort(peeringAddr.String())
+	if err != nil {
+		return false
+	}
+	return gossipHost == peeringHost
+}
+
+// subnetValidator caps the number of accepted neighbors sharing the same
+// IPv4 /N or IPv6 /N subnet, to limit a single operator's Sybil footprint.
+type subnetValidator struct {
+	sync.Mutex
+	ipv4MaskBits int
+	ipv6MaskBits int
+	maxPeers     int
+	counts       map[string]int
+}
+
+func newSubnetValidator() Validator {
+	v4Bits := defaultSubnetIPv4MaskBits
+	if parameter.NodeConfig.IsSet(CFG_P2P_SUBNET_IPV4_MASK_BITS) {
+		v4Bits = parameter.NodeConfig.GetInt(CFG_P2P_SUBNET_IPV4_MASK_BITS)
+	}
+	v6Bits := defaultSubnetIPv6MaskBits
+	if parameter.NodeConfig.IsSet(CFG_P2P_SUBNET_IPV6_MASK_BITS) {
+		v6Bits = parameter.NodeConfig.GetInt(CFG_P2P_SUBNET_IPV6_MASK_BITS)
+	}
+	maxPeers := defaultSubnetMaxPeers
+	if parameter.NodeConfig.IsSet(CFG_P2P_SUBNET_MAX_PEERS) {
+		maxPeers = parameter.NodeConfig.GetInt(CFG_P2P_SUBNET_MAX_PEERS)
+	}
+	v := &subnetValidator{ipv4MaskBits: v4Bits, ipv6MaskBits: v6Bits, maxPeers: maxPeers, counts: make(map[string]int)}
+	// Validate only vets candidates; only an actual neighbor occupies a slot,
+	// so the counters track NeighborAdded/NeighborRemoved, not Validate calls.
+	Events.NeighborAdded.Attach(events.NewClosure(v.onNeighborAdded))
+	Events.NeighborRemoved.Attach(events.NewClosure(v.onNeighborRemoved))
+	return v
+}
+
+func (v *subnetValidator) Name() string { return "subnet" }
+
+func (v *subnetValidator) Validate(p *peer.Peer) bool {
+	key, ok := v.subnetKey(p)
+	if !ok {
+		return true
+	}
+	v.Lock()
+	defer v.Unlock()
+	return v.counts[key] < v.maxPeers
+}
+
+func (v *subnetValidator) onNeighborAdded(p *peer.Peer) {
+	key, ok := v.subnetKey(p)
+	if !ok {
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	v.counts[key]++
+}
+
+func (v *subnetValidator) onNeighborRemoved(p *peer.Peer) {
+	key, ok := v.subnetKey(p)
+	if !ok {
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	if v.counts[key] == 0 {
+		return
+	}
+	v.counts[key]--
+	if v.counts[key] == 0 {
+		delete(v.counts, key)
+	}
+}
+
+// subnetKey returns the subnet p's peering address falls into, at this
+// validator's configured mask granularity.
+func (v *subnetValidator) subnetKey(p *peer.Peer) (string, bool) {
+	ip := peerIP(p)
+	if ip == nil {
+		return "", false
+	}
+	maskBits := v.ipv4MaskBits
+	if ip.To4() == nil {
+		maskBits = v.ipv6MaskBits
+	}
+	_, subnet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", ip.String(), maskBits))
+	if err != nil {
+		return "", false
+	}
+	return subnet.String(), true
+}
+
+// asnValidator caps the number of accepted neighbors sharing the same
+// Autonomous System Number, looked up in the MaxMind ASN database file at
+// p2p.asn.dbPath; no database is built in.
+type asnValidator struct {
+	sync.Mutex
+	reader   *geoip2.Reader
+	maxPeers int
+	counts   map[uint]int
+}
+
+func newASNValidator() Validator {
+	dbPath := parameter.NodeConfig.GetString(CFG_P2P_ASN_DB_PATH)
+	if dbPath == "" {
+		log.Info("ASN validator enabled but no p2p.asn.dbPath configured; skipping")
+		return nil
+	}
+	reader, err := geoip2.Open(dbPath)
+	if err != nil {
+		log.Warnf("Could not open ASN database %s: %v; skipping ASN validator", dbPath, err)
+		return nil
+	}
+	maxPeers := defaultASNMaxPeers
+	if parameter.NodeConfig.IsSet(CFG_P2P_ASN_MAX_PEERS) {
+		maxPeers = parameter.NodeConfig.GetInt(CFG_P2P_ASN_MAX_PEERS)
+	}
+	v := &asnValidator{reader: reader, maxPeers: maxPeers, counts: make(map[uint]int)}
+	// Same reasoning as subnetValidator: count actual neighbors, not every
+	// Validate call, so the cap can't be exhausted by re-validation or by
+	// candidates that end up rejected for unrelated reasons.
+	Events.NeighborAdded.Attach(events.NewClosure(v.onNeighborAdded))
+	Events.NeighborRemoved.Attach(events.NewClosure(v.onNeighborRemoved))
+	return v
+}
+
+func (v *asnValidator) Name() string { return "asn" }
+
+func (v *asnValidator) Validate(p *peer.Peer) bool {
+	asn, ok := v.asn(p)
+	if !ok {
+		return true
+	}
+	v.Lock()
+	defer v.Unlock()
+	return v.counts[asn] < v.maxPeers
+}
+
+func (v *asnValidator) onNeighborAdded(p *peer.Peer) {
+	asn, ok := v.asn(p)
+	if !ok {
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	v.counts[asn]++
+}
+
+func (v *asnValidator) onNeighborRemoved(p *peer.Peer) {
+	asn, ok := v.asn(p)
+	if !ok {
+		return
+	}
+	v.Lock()
+	defer v.Unlock()
+	if v.counts[asn] == 0 {
+		return
+	}
+	v.counts[asn]--
+	if v.counts[asn] == 0 {
+		delete(v.counts, asn)
+	}
+}
+
+// asn looks up the Autonomous System Number for p's peering address. ok is
+// false if p has no usable peering address or no ASN data is available for it.
+func (v *asnValidator) asn(p *peer.Peer) (uint, bool) {
+	ip := peerIP(p)
+	if ip == nil {
+		return 0, false
+	}
+	record, err := v.reader.ASN(ip)
+	if err != nil {
+		// no ASN data for this IP; don't penalize the peer for a lookup miss
+		return 0, false
+	}
+	return record.AutonomousSystemNumber, true
+}
+
+// metaKey is the service key under which a peer's software/protocol version
+// is advertised. It is a hornet-local addition, analogous to peeringKeyIPv6
+// in dualstack.go: hive.go's service package only defines PeeringKey and
+// GossipKey, so there is no upstream key for this.
+const metaKey service.Key = "meta"
+
+// minVersionValidator rejects peers that advertise a software/protocol
+// version older than the configured minimum, via metaKey.
+type minVersionValidator struct {
+	min [3]int
+}
+
+func newMinVersionValidator() Validator {
+	raw := parameter.NodeConfig.GetString(CFG_P2P_MIN_VERSION)
+	if raw == "" {
+		return nil
+	}
+	min, err := parseVersion(raw)
+	if err != nil {
+		log.Warnf("Invalid %s %q: %v; skipping version validator", CFG_P2P_MIN_VERSION, raw, err)
+		return nil
+	}
+	return minVersionValidator{min: min}
+}
+
+func (minVersionValidator) Name() string { return "version" }
+
+func (v minVersionValidator) Validate(p *peer.Peer) bool {
+	metaAddr := p.Services().Get(metaKey)
+	if metaAddr == nil {
+		// peers that don't advertise a version are treated as legacy/unknown
+		// and let through; operators wanting strict enforcement can combine
+		// this with the cidr/asn validators instead.
+		return true
+	}
+	peerVersion, err := parseVersion(metaAddr.String())
+	if err != nil {
+		return true
+	}
+	return compareVersions(peerVersion, v.min) >= 0
+}
+
+func parseVersion(raw string) ([3]int, error) {
+	var v [3]int
+	parts := strings.SplitN(raw, ".", 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return v, fmt.Errorf("invalid version component %q: %w", parts[i], err)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+// cidrValidator evaluates an optional allow-list and deny-list of CIDRs
+// against a peer's address.
+type cidrValidator struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+func newCIDRValidator() Validator {
+	allow := parseCIDRList(parameter.NodeConfig.GetStringSlice(CFG_P2P_ALLOWED_CIDRS))
+	deny := parseCIDRList(parameter.NodeConfig.GetStringSlice(CFG_P2P_DENIED_CIDRS))
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+	return cidrValidator{allow: allow, deny: deny}
+}
+
+func (v cidrValidator) Name() string { return "cidr" }
+
+func (v cidrValidator) Validate(p *peer.Peer) bool {
+	ip := peerIP(p)
+	if ip == nil {
+		return true
+	}
+	for _, denied := range v.deny {
+		if denied.Contains(ip) {
+			return false
+		}
+	}
+	if len(v.allow) == 0 {
+		return true
+	}
+	for _, allowed := range v.allow {
+		if allowed.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRList(raw []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, entry := range raw {
+		if entry == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Warnf("Invalid CIDR %q; ignoring", entry)
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// peerIP extracts the advertised peering IP address of p, preferring IPv4.
+func peerIP(p *peer.Peer) net.IP {
+	addr := p.Services().Get(service.PeeringKey)
+	if addr == nil {
+		addr = p.Services().Get(peeringKeyIPv6)
+	}
+	if addr == nil {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
+var _ selection.ValidatorFunc = (*ValidatorChain)(nil).Validate
@@ -0,0 +1,116 @@
+package autopeering
+
+import (
+	"net"
+	"testing"
+
+	"github.com/iotaledger/hive.go/autopeering/peer"
+	"github.com/iotaledger/hive.go/autopeering/peer/service"
+)
+
+// peerWithPeeringAddr builds a minimal *peer.Peer advertising addr under
+// service.PeeringKey, for validators that only look at the peering address.
+func peerWithPeeringAddr(t *testing.T, addr string) *peer.Peer {
+	t.Helper()
+	services := service.New()
+	services.Update(service.PeeringKey, "udp", addr)
+	return peer.NewPeer([]byte("01234567890123456789012345678901"), services)
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    [3]int
+		wantErr bool
+	}{
+		{raw: "1.2.3", want: [3]int{1, 2, 3}},
+		{raw: "1.2", want: [3]int{1, 2, 0}},
+		{raw: "1", want: [3]int{1, 0, 0}},
+		{raw: "1.2.3.4", wantErr: true}, // SplitN(3) leaves "3.4" as the third component, which fails Atoi
+		{raw: "1.x.3", wantErr: true},
+		{raw: "", wantErr: true}, // SplitN yields a single empty component, which fails Atoi
+	}
+	for _, tt := range tests {
+		got, err := parseVersion(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseVersion(%q): expected an error, got none", tt.raw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseVersion(%q): unexpected error: %v", tt.raw, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseVersion(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b [3]int
+		want int
+	}{
+		{a: [3]int{1, 0, 0}, b: [3]int{1, 0, 0}, want: 0},
+		{a: [3]int{1, 2, 0}, b: [3]int{1, 1, 9}, want: 1},
+		{a: [3]int{1, 1, 9}, b: [3]int{1, 2, 0}, want: -1},
+		{a: [3]int{2, 0, 0}, b: [3]int{1, 9, 9}, want: 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compareVersions(%v, %v) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSubnetValidatorSubnetKey(t *testing.T) {
+	v := &subnetValidator{ipv4MaskBits: 24, ipv6MaskBits: 64, maxPeers: 1, counts: make(map[string]int)}
+
+	peerA := peerWithPeeringAddr(t, "192.0.2.10:14626")
+	peerB := peerWithPeeringAddr(t, "192.0.2.20:14626")
+
+	keyA, ok := v.subnetKey(peerA)
+	if !ok {
+		t.Fatalf("expected a subnet key for %s", peerA.ID())
+	}
+	keyB, ok := v.subnetKey(peerB)
+	if !ok {
+		t.Fatalf("expected a subnet key for %s", peerB.ID())
+	}
+	if keyA != keyB {
+		t.Fatalf("expected 192.0.2.10 and 192.0.2.20 to share a /24 subnet key, got %q and %q", keyA, keyB)
+	}
+
+	peerC := peerWithPeeringAddr(t, "198.51.100.5:14626")
+	keyC, ok := v.subnetKey(peerC)
+	if !ok {
+		t.Fatalf("expected a subnet key for %s", peerC.ID())
+	}
+	if keyA == keyC {
+		t.Fatalf("expected 192.0.2.10 and 198.51.100.5 to fall into different /24 subnets")
+	}
+}
+
+func TestCIDRValidator(t *testing.T) {
+	_, allowed, err := net.ParseCIDR("192.0.2.0/24")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, denied, err := net.ParseCIDR("192.0.2.128/25")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v := cidrValidator{allow: []*net.IPNet{allowed}, deny: []*net.IPNet{denied}}
+
+	if !v.Validate(peerWithPeeringAddr(t, "192.0.2.10:14626")) {
+		t.Fatalf("expected an allowed, non-denied address to validate")
+	}
+	if v.Validate(peerWithPeeringAddr(t, "192.0.2.200:14626")) {
+		t.Fatalf("expected the denied sub-range to win over the broader allow range")
+	}
+	if v.Validate(peerWithPeeringAddr(t, "203.0.113.1:14626")) {
+		t.Fatalf("expected an address outside the allow-list to be rejected")
+	}
+}